@@ -0,0 +1,394 @@
+package dwarf
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+)
+
+// DwoResolver resolves a skeleton compile unit, found in a main executable
+// built with -gsplit-dwarf, to the full DIE tree describing it, which is
+// kept in a separate split DWARF object (.dwo) or package (.dwp) file.
+type DwoResolver interface {
+	// LookupCU returns the DWARF data containing the compile unit
+	// identified by dwoID, the value of a skeleton unit's
+	// DW_AT_GNU_dwo_id/DW_AT_dwo_id attribute.
+	LookupCU(dwoID uint64) (*dwarf.Data, error)
+
+	// LookupTU returns the DWARF data containing the type unit identified
+	// by typeSignature, the value of a DW_UT_split_type unit's
+	// DW_AT_signature attribute (also UnitHeader.TypeSignature).
+	LookupTU(typeSignature uint64) (*dwarf.Data, error)
+}
+
+// dwoSectionNames are the debug_*.dwo sections read out of a standalone
+// .dwo file or out of the packed sections of a .dwp file.
+var dwoSectionNames = []string{"abbrev", "info", "str", "str_offsets", "line"}
+
+// OpenDWOResolver returns a DwoResolver for the split DWARF data of a
+// skeleton compile unit. compDir and dwoName are the unit's DW_AT_comp_dir
+// and DW_AT_GNU_dwo_name (or DW_AT_dwo_name) attributes. If dwpPath is
+// non-empty the unit is looked up by DWO ID in that .dwp package instead
+// of loading a standalone .dwo file. debugAddr is the contents of the
+// .debug_addr section of the main executable (not the .dwo/.dwp), which is
+// where DW_FORM_addrx operands of the split unit are indexed into, keyed
+// by the skeleton unit's DW_AT_addr_base.
+func OpenDWOResolver(compDir, dwoName, dwpPath string, debugAddr []byte) (DwoResolver, error) {
+	if dwpPath != "" {
+		return LoadDWPFile(dwpPath, debugAddr)
+	}
+
+	path := dwoName
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(compDir, dwoName)
+	}
+	return LoadDWOFile(path, debugAddr)
+}
+
+// dwoFileResolver implements DwoResolver for a single standalone .dwo
+// file. A standalone .dwo file only ever contains the one compile unit it
+// was split off from, so dwoID is not used to pick anything, LookupCU just
+// returns it.
+type dwoFileResolver struct {
+	data *dwarf.Data
+}
+
+// LoadDWOFile opens the standalone split DWARF object file at path. See
+// OpenDWOResolver for the meaning of debugAddr.
+func LoadDWOFile(path string, debugAddr []byte) (DwoResolver, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open DWO file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	sections, err := readDwoSections(f, dwoSectionNames)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := newDwoData(sections, debugAddr)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse DWO file %s: %v", path, err)
+	}
+
+	return &dwoFileResolver{data: data}, nil
+}
+
+func (r *dwoFileResolver) LookupCU(dwoID uint64) (*dwarf.Data, error) {
+	return r.data, nil
+}
+
+// LookupTU is the type-unit equivalent of LookupCU: a standalone .dwo file
+// contains every split type unit referenced by the compile unit it was
+// split off from in the same info section, already combined into r.data by
+// dwarf.New, so there's nothing left to pick out by typeSignature.
+func (r *dwoFileResolver) LookupTU(typeSignature uint64) (*dwarf.Data, error) {
+	return r.data, nil
+}
+
+// newDwoData builds a *dwarf.Data out of the .debug_*.dwo sections of a
+// split DWARF unit (sections, keyed without the .dwo suffix) plus the
+// .debug_addr section of the main executable (debugAddr, may be nil). The
+// str_offsets and addr sections aren't accepted by dwarf.New directly -
+// DWARF5 added them after that function's signature was fixed - so they're
+// registered afterwards with AddSection. Without them, debug/dwarf fails
+// outright on the indexed string/address forms (DW_FORM_strx/DW_FORM_addrx)
+// that -gsplit-dwarf output uses throughout.
+func newDwoData(sections map[string][]byte, debugAddr []byte) (*dwarf.Data, error) {
+	data, err := dwarf.New(sections["abbrev"], nil, nil, sections["info"], sections["line"], nil, nil, sections["str"])
+	if err != nil {
+		return nil, err
+	}
+
+	if so := sections["str_offsets"]; so != nil {
+		if err := data.AddSection(".debug_str_offsets", so); err != nil {
+			return nil, fmt.Errorf("could not add .debug_str_offsets section: %v", err)
+		}
+	}
+	if debugAddr != nil {
+		if err := data.AddSection(".debug_addr", debugAddr); err != nil {
+			return nil, fmt.Errorf("could not add .debug_addr section: %v", err)
+		}
+	}
+
+	return data, nil
+}
+
+// readDwoSections reads the contents of the .debug_<name>.dwo sections of
+// f, for each name in names. A missing section reads as nil, matching the
+// zero-value behavior debug/dwarf.New expects for sections that aren't
+// present.
+func readDwoSections(f *elf.File, names []string) (map[string][]byte, error) {
+	sections := make(map[string][]byte, len(names))
+	for _, name := range names {
+		sec := f.Section(".debug_" + name + ".dwo")
+		if sec == nil {
+			continue
+		}
+		data, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("could not read .debug_%s.dwo: %v", name, err)
+		}
+		sections[name] = data
+	}
+	return sections, nil
+}
+
+// dwpResolver implements DwoResolver for a .dwp package file, which bundles
+// together the split DWARF of every compile unit produced by a link, and
+// indexes them by DWO ID so that a single unit's sections can be sliced
+// back out again.
+type dwpResolver struct {
+	cuIndex   dwpIndex          // .debug_cu_index, keyed by DWO ID
+	tuIndex   dwpIndex          // .debug_tu_index, keyed by type signature; zero value if absent
+	raw       map[string][]byte // raw, still-packed contents of each .debug_*.dwo section
+	debugAddr []byte            // .debug_addr section of the main executable, see OpenDWOResolver
+}
+
+// LoadDWPFile opens the split DWARF package file at path. See
+// OpenDWOResolver for the meaning of debugAddr.
+func LoadDWPFile(path string, debugAddr []byte) (DwoResolver, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open DWP file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	idxsec := f.Section(".debug_cu_index")
+	if idxsec == nil {
+		return nil, fmt.Errorf("%s has no .debug_cu_index section", path)
+	}
+	idxdata, err := idxsec.Data()
+	if err != nil {
+		return nil, fmt.Errorf("could not read .debug_cu_index: %v", err)
+	}
+
+	cuIndex, err := readDWPIndex(idxdata, binary.LittleEndian)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse .debug_cu_index of %s: %v", path, err)
+	}
+
+	// .debug_tu_index is optional: a .dwp with no split type units, or
+	// produced by a linker that packs type units into .debug_cu_index
+	// instead, simply won't have one.
+	var tuIndex dwpIndex
+	if tuSec := f.Section(".debug_tu_index"); tuSec != nil {
+		tudata, err := tuSec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("could not read .debug_tu_index: %v", err)
+		}
+		tuIndex, err = readDWPIndex(tudata, binary.LittleEndian)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse .debug_tu_index of %s: %v", path, err)
+		}
+	}
+
+	raw, err := readDwoSections(f, dwoSectionNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dwpResolver{cuIndex: cuIndex, tuIndex: tuIndex, raw: raw, debugAddr: debugAddr}, nil
+}
+
+// lookupSignature looks signature up in idx and builds the *dwarf.Data of
+// the unit it identifies, shared by LookupCU and LookupTU which differ only
+// in which index (and which kind of signature) they look up.
+func (r *dwpResolver) lookupSignature(idx dwpIndex, signature uint64) (*dwarf.Data, error) {
+	row, ok := idx.lookup(signature)
+	if !ok {
+		return nil, fmt.Errorf("signature %#x not found in package index", signature)
+	}
+
+	slice := func(name string) []byte {
+		off, size, ok := idx.sectionSlice(row, name)
+		if !ok {
+			return nil
+		}
+		data := r.raw[name]
+		if int(off+size) > len(data) {
+			return nil
+		}
+		return data[off : off+size]
+	}
+
+	sections := make(map[string][]byte, len(dwoSectionNames))
+	for _, name := range dwoSectionNames {
+		sections[name] = slice(name)
+	}
+
+	return newDwoData(sections, r.debugAddr)
+}
+
+func (r *dwpResolver) LookupCU(dwoID uint64) (*dwarf.Data, error) {
+	return r.lookupSignature(r.cuIndex, dwoID)
+}
+
+func (r *dwpResolver) LookupTU(typeSignature uint64) (*dwarf.Data, error) {
+	return r.lookupSignature(r.tuIndex, typeSignature)
+}
+
+// DW_SECT_* identifiers used by the section offsets/sizes tables of a .dwp
+// index, see DWARF5 table 7.1.
+const (
+	dwSectInfo       = 1
+	dwSectAbbrev     = 3
+	dwSectLine       = 4
+	dwSectLoclists   = 5
+	dwSectStrOffsets = 6
+	dwSectMacro      = 7
+	dwSectRnglists   = 8
+)
+
+var dwpSectionNames = map[uint32]string{
+	dwSectInfo:       "info",
+	dwSectAbbrev:     "abbrev",
+	dwSectLine:       "line",
+	dwSectLoclists:   "loclists",
+	dwSectStrOffsets: "str_offsets",
+	dwSectMacro:      "macro",
+	dwSectRnglists:   "rnglists",
+}
+
+// dwpIndex is a parsed .debug_cu_index or .debug_tu_index section: a
+// hash-table keyed by 64-bit signature (DWO ID or type signature) mapping
+// to a row of the section offsets/sizes tables, see DWARF5 Appendix F.
+type dwpIndex struct {
+	unitCount  uint32
+	slotCount  uint32
+	sectionIDs []uint32 // one per column of the offsets/sizes tables
+	slotSigs   []uint64 // slotCount signatures, zero for empty slots
+	slotRows   []uint32 // slotCount row indices (1-based), zero for empty slots
+	offsets    []uint32 // unitCount * len(sectionIDs)
+	sizes      []uint32 // unitCount * len(sectionIDs)
+}
+
+// readDWPIndex parses the header, hash table, row index table and section
+// offsets/sizes tables of a .dwp index section.
+func readDWPIndex(data []byte, order binary.ByteOrder) (dwpIndex, error) {
+	if len(data) < 16 {
+		return dwpIndex{}, fmt.Errorf("index truncated")
+	}
+
+	version := order.Uint32(data)
+	if version < 2 {
+		return dwpIndex{}, fmt.Errorf("unsupported package index version %d", version)
+	}
+
+	sectionCount := order.Uint32(data[4:])
+	idx := dwpIndex{
+		unitCount: order.Uint32(data[8:]),
+		slotCount: order.Uint32(data[12:]),
+	}
+
+	off := 16
+	// readCount reads count contiguous elemSize-byte entries starting at
+	// off, checking count against the bytes actually left in data first.
+	// Without this check a corrupt or truncated index with huge counts
+	// (header fields are attacker/corruption-controlled) would panic the
+	// make() calls below instead of returning the "index truncated" error
+	// every other short-read path here produces.
+	readCount := func(count uint64, elemSize int) ([]byte, error) {
+		if count > uint64(len(data)-off)/uint64(elemSize) {
+			return nil, fmt.Errorf("index truncated")
+		}
+		n := int(count) * elemSize
+		b := data[off : off+n]
+		off += n
+		return b, nil
+	}
+
+	sigBytes, err := readCount(uint64(idx.slotCount), 8)
+	if err != nil {
+		return dwpIndex{}, err
+	}
+	idx.slotSigs = make([]uint64, idx.slotCount)
+	for i := range idx.slotSigs {
+		idx.slotSigs[i] = order.Uint64(sigBytes[i*8:])
+	}
+
+	rowBytes, err := readCount(uint64(idx.slotCount), 4)
+	if err != nil {
+		return dwpIndex{}, err
+	}
+	idx.slotRows = make([]uint32, idx.slotCount)
+	for i := range idx.slotRows {
+		idx.slotRows[i] = order.Uint32(rowBytes[i*4:])
+	}
+
+	idBytes, err := readCount(uint64(sectionCount), 4)
+	if err != nil {
+		return dwpIndex{}, err
+	}
+	idx.sectionIDs = make([]uint32, sectionCount)
+	for i := range idx.sectionIDs {
+		idx.sectionIDs[i] = order.Uint32(idBytes[i*4:])
+	}
+
+	n := uint64(idx.unitCount) * uint64(sectionCount)
+
+	offBytes, err := readCount(n, 4)
+	if err != nil {
+		return dwpIndex{}, err
+	}
+	idx.offsets = make([]uint32, n)
+	for i := range idx.offsets {
+		idx.offsets[i] = order.Uint32(offBytes[i*4:])
+	}
+
+	sizeBytes, err := readCount(n, 4)
+	if err != nil {
+		return dwpIndex{}, err
+	}
+	idx.sizes = make([]uint32, n)
+	for i := range idx.sizes {
+		idx.sizes[i] = order.Uint32(sizeBytes[i*4:])
+	}
+
+	return idx, nil
+}
+
+// lookup finds the 1-based row of the offsets/sizes tables for the given
+// signature, using the open-addressing probe sequence from DWARF5
+// Appendix F.1.
+func (idx dwpIndex) lookup(signature uint64) (uint32, bool) {
+	if idx.slotCount == 0 {
+		return 0, false
+	}
+
+	mask := uint64(idx.slotCount - 1)
+	h := signature & mask
+	h2 := ((signature >> 32) & mask) | 1
+
+	for i := uint64(0); i < uint64(idx.slotCount); i++ {
+		row := idx.slotRows[h]
+		if row == 0 {
+			return 0, false
+		}
+		if idx.slotSigs[h] == signature {
+			return row, true
+		}
+		h = (h + h2) & mask
+	}
+	return 0, false
+}
+
+// sectionSlice returns the offset and size, within the packed
+// .debug_<name>.dwo section, of the slice belonging to the unit at row
+// (1-based, as returned by lookup).
+func (idx dwpIndex) sectionSlice(row uint32, name string) (off, size uint32, ok bool) {
+	if row == 0 || row > idx.unitCount {
+		return 0, 0, false
+	}
+	for col, id := range idx.sectionIDs {
+		if dwpSectionNames[id] != name {
+			continue
+		}
+		i := int(row-1)*len(idx.sectionIDs) + col
+		return idx.offsets[i], idx.sizes[i], true
+	}
+	return 0, 0, false
+}
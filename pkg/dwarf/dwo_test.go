@@ -0,0 +1,188 @@
+package dwarf
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"encoding/binary"
+	"testing"
+)
+
+// buildDWPIndex builds a minimal .debug_cu_index/.debug_tu_index section
+// with a single populated slot, one unit and one section column (info).
+func buildDWPIndex(order binary.ByteOrder, slotCount uint32, sig uint64, row uint32, offset, size uint32) []byte {
+	var buf []byte
+	buf = AppendUint(buf, order, 4, 2) // version
+	buf = AppendUint(buf, order, 4, 1) // section count
+	buf = AppendUint(buf, order, 4, 1) // unit count
+	buf = AppendUint(buf, order, 4, uint64(slotCount))
+
+	slotSigs := make([]uint64, slotCount)
+	slotRows := make([]uint32, slotCount)
+	mask := uint64(slotCount - 1)
+	h := sig & mask
+	slotSigs[h] = sig
+	slotRows[h] = row
+
+	for _, s := range slotSigs {
+		buf = AppendUint(buf, order, 8, s)
+	}
+	for _, r := range slotRows {
+		buf = AppendUint(buf, order, 4, uint64(r))
+	}
+
+	buf = AppendUint(buf, order, 4, uint64(dwSectInfo)) // section IDs
+	buf = AppendUint(buf, order, 4, uint64(offset))     // offsets table
+	buf = AppendUint(buf, order, 4, uint64(size))       // sizes table
+
+	return buf
+}
+
+func TestDWPIndexLookup(t *testing.T) {
+	const sig = 0x1
+	data := buildDWPIndex(binary.LittleEndian, 4, sig, 1, 5, 10)
+
+	idx, err := readDWPIndex(data, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("readDWPIndex: %v", err)
+	}
+
+	row, ok := idx.lookup(sig)
+	if !ok || row != 1 {
+		t.Fatalf("lookup(%#x) = (%d, %v), want (1, true)", sig, row, ok)
+	}
+
+	if _, ok := idx.lookup(sig + 1); ok {
+		t.Errorf("lookup(%#x) unexpectedly found a row", sig+1)
+	}
+
+	off, size, ok := idx.sectionSlice(row, "info")
+	if !ok || off != 5 || size != 10 {
+		t.Errorf("sectionSlice(%d, info) = (%d, %d, %v), want (5, 10, true)", row, off, size, ok)
+	}
+
+	if _, _, ok := idx.sectionSlice(row, "abbrev"); ok {
+		t.Errorf("sectionSlice(%d, abbrev) unexpectedly found a slice", row)
+	}
+}
+
+func TestDWPIndexEmpty(t *testing.T) {
+	var buf []byte
+	buf = AppendUint(buf, binary.LittleEndian, 4, 2) // version
+	buf = AppendUint(buf, binary.LittleEndian, 4, 0) // section count
+	buf = AppendUint(buf, binary.LittleEndian, 4, 0) // unit count
+	buf = AppendUint(buf, binary.LittleEndian, 4, 0) // slot count
+
+	idx, err := readDWPIndex(buf, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("readDWPIndex: %v", err)
+	}
+
+	if _, ok := idx.lookup(0x1234); ok {
+		t.Errorf("lookup on an empty index unexpectedly found a row")
+	}
+}
+
+func TestDWPIndexTruncated(t *testing.T) {
+	data := buildDWPIndex(binary.LittleEndian, 4, 0x1, 1, 5, 10)
+
+	if _, err := readDWPIndex(data[:len(data)-1], binary.LittleEndian); err == nil {
+		t.Errorf("expected an error parsing a truncated index, got nil")
+	}
+	if _, err := readDWPIndex(data[:8], binary.LittleEndian); err == nil {
+		t.Errorf("expected an error parsing a header-only index, got nil")
+	}
+}
+
+func TestDWPIndexHugeCounts(t *testing.T) {
+	// unitCount and sectionCount are taken straight from the section
+	// header; huge values (attacker/corruption-controlled) must produce
+	// an error, not panic make() when sized against a tiny buffer.
+	var buf []byte
+	buf = AppendUint(buf, binary.LittleEndian, 4, 2)          // version
+	buf = AppendUint(buf, binary.LittleEndian, 4, 0xffffffff) // section count
+	buf = AppendUint(buf, binary.LittleEndian, 4, 0xffffffff) // unit count
+	buf = AppendUint(buf, binary.LittleEndian, 4, 0)          // slot count
+
+	if _, err := readDWPIndex(buf, binary.LittleEndian); err == nil {
+		t.Errorf("expected an error parsing an index with huge counts, got nil")
+	}
+}
+
+// buildStrxCU builds a minimal DWARF5 compile unit whose single DIE has a
+// DW_AT_name attribute encoded with DW_FORM_strx1, index 0 - the form split
+// DWARF uses pervasively for strings, and which debug/dwarf can only
+// resolve once a .debug_str_offsets section has been registered.
+func buildStrxCU() (abbrev, info []byte) {
+	const (
+		dwTagCompileUnit = 0x11
+		dwAtName         = 0x03
+		dwFormStrx1      = 0x25
+	)
+
+	abbrev = appendULEB(abbrev, 1) // abbrev code
+	abbrev = appendULEB(abbrev, dwTagCompileUnit)
+	abbrev = append(abbrev, 0) // no children
+	abbrev = appendULEB(abbrev, dwAtName)
+	abbrev = appendULEB(abbrev, dwFormStrx1)
+	abbrev = appendULEB(abbrev, 0) // attribute list terminator
+	abbrev = appendULEB(abbrev, 0)
+	abbrev = append(abbrev, 0) // abbrev table terminator
+
+	body := AppendUint(nil, binary.LittleEndian, 2, 5) // version
+	body = append(body, 1, 8)                          // unit_type=DW_UT_compile, address_size
+	body = AppendUint(body, binary.LittleEndian, 4, 0) // debug_abbrev_offset
+	body = appendULEB(body, 1)                         // abbrev code
+	body = append(body, 0)                             // strx1 index 0
+
+	info = AppendUint(nil, binary.LittleEndian, 4, uint64(len(body)))
+	info = append(info, body...)
+	return abbrev, info
+}
+
+func appendULEB(buf []byte, v uint64) []byte {
+	var b bytes.Buffer
+	WriteULEB128(&b, v)
+	return append(buf, b.Bytes()...)
+}
+
+func TestNewDwoDataResolvesIndexedStrings(t *testing.T) {
+	abbrev, info := buildStrxCU()
+	sections := map[string][]byte{
+		"abbrev": abbrev,
+		"info":   info,
+		"str":    {0}, // one empty, nul-terminated string at offset 0
+	}
+
+	data, err := newDwoData(sections, nil)
+	if err != nil {
+		t.Fatalf("newDwoData: %v", err)
+	}
+	if _, err := data.Reader().Next(); err == nil {
+		t.Fatalf("expected an error resolving DW_FORM_strx1 without .debug_str_offsets, got nil")
+	}
+
+	sections["str_offsets"] = []byte{0, 0, 0, 0} // one entry, pointing at str offset 0
+	data, err = newDwoData(sections, nil)
+	if err != nil {
+		t.Fatalf("newDwoData: %v", err)
+	}
+	entry, err := data.Reader().Next()
+	if err != nil {
+		t.Fatalf("Next with .debug_str_offsets registered: %v", err)
+	}
+	if name, _ := entry.Val(dwarf.AttrName).(string); name != "" {
+		t.Errorf("AttrName = %q, want empty string", name)
+	}
+}
+
+func TestNewDwoDataWithoutOptionalSections(t *testing.T) {
+	abbrev, info := buildStrxCU()
+	sections := map[string][]byte{
+		"abbrev": abbrev,
+		"info":   info,
+		"str":    {0},
+	}
+	if _, err := newDwoData(sections, nil); err != nil {
+		t.Fatalf("newDwoData with no optional sections: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package dwarf
+
+import "io"
+
+// ReadULEB128 reads an unsigned LEB128 encoded integer from r, returning
+// the decoded value and the number of bytes consumed.
+func ReadULEB128(r io.ByteReader) (uint64, int, error) {
+	var (
+		result uint64
+		shift  uint
+		n      int
+	)
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, n, err
+		}
+		n++
+
+		if shift < 64 {
+			result |= uint64(b&0x7f) << shift
+		}
+		shift += 7
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	return result, n, nil
+}
+
+// ReadSLEB128 reads a signed LEB128 encoded integer from r, returning the
+// decoded value and the number of bytes consumed.
+func ReadSLEB128(r io.ByteReader) (int64, int, error) {
+	var (
+		result int64
+		shift  uint
+		n      int
+		b      byte
+		err    error
+	)
+
+	for {
+		b, err = r.ReadByte()
+		if err != nil {
+			return 0, n, err
+		}
+		n++
+
+		if shift < 64 {
+			result |= int64(b&0x7f) << shift
+		}
+		shift += 7
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	if shift < 64 && b&0x40 != 0 {
+		result |= -1 << shift
+	}
+
+	return result, n, nil
+}
+
+// DecodeULEB128 decodes an unsigned LEB128 encoded integer from the start
+// of buf, returning the decoded value and the number of bytes consumed.
+// It does not allocate, unlike ReadULEB128, making it suitable for hot
+// paths (line number programs, location lists) that already have the
+// whole input in a byte slice.
+func DecodeULEB128(buf []byte) (uint64, int) {
+	var (
+		result uint64
+		shift  uint
+		n      int
+	)
+
+	for n < len(buf) {
+		b := buf[n]
+		n++
+
+		if shift < 64 {
+			result |= uint64(b&0x7f) << shift
+		}
+		shift += 7
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	return result, n
+}
+
+// DecodeSLEB128 decodes a signed LEB128 encoded integer from the start of
+// buf, returning the decoded value and the number of bytes consumed. See
+// DecodeULEB128.
+func DecodeSLEB128(buf []byte) (int64, int) {
+	var (
+		result int64
+		shift  uint
+		n      int
+		b      byte
+	)
+
+	for n < len(buf) {
+		b = buf[n]
+		n++
+
+		if shift < 64 {
+			result |= int64(b&0x7f) << shift
+		}
+		shift += 7
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	if shift < 64 && b&0x40 != 0 {
+		result |= -1 << shift
+	}
+
+	return result, n
+}
+
+// WriteULEB128 writes x to w, encoded as unsigned LEB128.
+func WriteULEB128(w io.Writer, x uint64) error {
+	for {
+		b := byte(x & 0x7f)
+		x >>= 7
+		if x != 0 {
+			b |= 0x80
+		}
+		if _, err := w.Write([]byte{b}); err != nil {
+			return err
+		}
+		if x == 0 {
+			return nil
+		}
+	}
+}
+
+// WriteSLEB128 writes x to w, encoded as signed LEB128.
+func WriteSLEB128(w io.Writer, x int64) error {
+	for {
+		b := byte(x & 0x7f)
+		x >>= 7
+
+		signBitSet := b&0x40 != 0
+		done := (x == 0 && !signBitSet) || (x == -1 && signBitSet)
+		if !done {
+			b |= 0x80
+		}
+		if _, err := w.Write([]byte{b}); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package dwarf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestULEB128RoundTrip(t *testing.T) {
+	values := []uint64{0, 1, 0x7f, 0x80, 0x81, 0xff, 0x4000, 1 << 35, ^uint64(0)}
+
+	for _, v := range values {
+		var buf bytes.Buffer
+		if err := WriteULEB128(&buf, v); err != nil {
+			t.Fatalf("WriteULEB128(%#x): %v", v, err)
+		}
+
+		got, n, err := ReadULEB128(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ReadULEB128(%#x): %v", v, err)
+		}
+		if got != v {
+			t.Errorf("ReadULEB128 round trip of %#x = %#x", v, got)
+		}
+		if n != buf.Len() {
+			t.Errorf("ReadULEB128(%#x): consumed %d bytes, want %d", v, n, buf.Len())
+		}
+
+		got2, n2 := DecodeULEB128(buf.Bytes())
+		if got2 != v || n2 != n {
+			t.Errorf("DecodeULEB128(%#x) = (%#x, %d), want (%#x, %d)", v, got2, n2, v, n)
+		}
+	}
+}
+
+func TestSLEB128RoundTrip(t *testing.T) {
+	values := []int64{0, 1, -1, 63, -64, 64, -65, 1000000, -1000000, int64(1) << 40, -(int64(1) << 40)}
+
+	for _, v := range values {
+		var buf bytes.Buffer
+		if err := WriteSLEB128(&buf, v); err != nil {
+			t.Fatalf("WriteSLEB128(%d): %v", v, err)
+		}
+
+		got, n, err := ReadSLEB128(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ReadSLEB128(%d): %v", v, err)
+		}
+		if got != v {
+			t.Errorf("ReadSLEB128 round trip of %d = %d", v, got)
+		}
+		if n != buf.Len() {
+			t.Errorf("ReadSLEB128(%d): consumed %d bytes, want %d", v, n, buf.Len())
+		}
+
+		got2, n2 := DecodeSLEB128(buf.Bytes())
+		if got2 != v || n2 != n {
+			t.Errorf("DecodeSLEB128(%d) = (%d, %d), want (%d, %d)", v, got2, n2, v, n)
+		}
+	}
+}
+
+func TestULEB128KnownEncoding(t *testing.T) {
+	// 624485 is the canonical DWARF spec example for ULEB128: encoded as
+	// 0xe5 0x8e 0x26.
+	want := []byte{0xe5, 0x8e, 0x26}
+
+	var buf bytes.Buffer
+	if err := WriteULEB128(&buf, 624485); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("WriteULEB128(624485) = %x, want %x", buf.Bytes(), want)
+	}
+}
+
+func TestSLEB128KnownEncoding(t *testing.T) {
+	// -624485 is the canonical DWARF spec example for SLEB128: encoded as
+	// 0x9b 0xf1 0x59.
+	want := []byte{0x9b, 0xf1, 0x59}
+
+	var buf bytes.Buffer
+	if err := WriteSLEB128(&buf, -624485); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("WriteSLEB128(-624485) = %x, want %x", buf.Bytes(), want)
+	}
+}
+
+func TestReadULEB128Truncated(t *testing.T) {
+	// A continuation byte (high bit set) with nothing after it.
+	buf := []byte{0x80}
+	if _, _, err := ReadULEB128(bytes.NewReader(buf)); err == nil {
+		t.Errorf("expected an error reading a truncated ULEB128, got nil")
+	}
+}
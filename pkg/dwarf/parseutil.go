@@ -46,6 +46,8 @@ func ReadUintRaw(reader io.Reader, order binary.ByteOrder, ptrSize int) (uint64,
 // WriteUint writes an integer of ptrSize bytes to writer, in the specified byte order.
 func WriteUint(writer io.Writer, order binary.ByteOrder, ptrSize int, data uint64) error {
 	switch ptrSize {
+	case 2:
+		return binary.Write(writer, order, uint16(data))
 	case 4:
 		return binary.Write(writer, order, uint32(data))
 	case 8:
@@ -54,7 +56,52 @@ func WriteUint(writer io.Writer, order binary.ByteOrder, ptrSize int, data uint6
 	return fmt.Errorf("pointer size %d not supported", ptrSize)
 }
 
-// ReadDwarfLengthVersion reads a DWARF length field followed by a version field
+// AppendUint appends an integer of ptrSize bytes to dst, in the specified
+// byte order, and returns the extended slice. It is equivalent to WriteUint
+// into a bytes.Buffer, without the allocation that wrapping a slice in one
+// requires.
+func AppendUint(dst []byte, order binary.ByteOrder, ptrSize int, data uint64) []byte {
+	var buf [8]byte
+	switch ptrSize {
+	case 2:
+		order.PutUint16(buf[:2], uint16(data))
+	case 4:
+		order.PutUint32(buf[:4], uint32(data))
+	case 8:
+		order.PutUint64(buf[:8], data)
+	default:
+		panic(fmt.Errorf("pointer size %d not supported", ptrSize))
+	}
+	return append(dst, buf[:ptrSize]...)
+}
+
+// ReadUintRawAt reads an integer of ptrSize bytes, with the specified byte
+// order, starting at offset off in buf, and returns it along with the
+// offset of the first byte after it. It is equivalent to calling
+// ReadUintRaw on a bytes.Reader wrapping buf[off:], without the allocation.
+func ReadUintRawAt(buf []byte, off int, order binary.ByteOrder, ptrSize int) (uint64, int, error) {
+	if off < 0 || off > len(buf)-ptrSize {
+		return 0, off, io.ErrUnexpectedEOF
+	}
+	switch ptrSize {
+	case 2:
+		return uint64(order.Uint16(buf[off:])), off + ptrSize, nil
+	case 4:
+		return uint64(order.Uint32(buf[off:])), off + ptrSize, nil
+	case 8:
+		return order.Uint64(buf[off:]), off + ptrSize, nil
+	}
+	return 0, off, fmt.Errorf("pointer size %d not supported", ptrSize)
+}
+
+// ReadDwarfLengthVersion reads a DWARF length field followed by a version
+// field, guessing the byte order from the high byte of the version field.
+// This heuristic is wrong for DWARF 5 versions >= 256 on little-endian (not
+// a real concern, DWARF hasn't gotten anywhere near that) but, more
+// importantly, also wrong for any legitimate big-endian version whose low
+// byte happens to be zero. Kept only for backwards compatibility with
+// existing callers that don't already know the byte order of the section;
+// prefer ReadDwarfLengthVersionOrder with an explicitly known byte order.
 func ReadDwarfLengthVersion(data []byte) (length uint64, dwarf64 bool, version uint8, byteOrder binary.ByteOrder) {
 	if len(data) < 4 {
 		return 0, false, 0, binary.LittleEndian
@@ -63,7 +110,6 @@ func ReadDwarfLengthVersion(data []byte) (length uint64, dwarf64 bool, version u
 	lengthfield := binary.LittleEndian.Uint32(data)
 	voff := 4
 	if lengthfield == ^uint32(0) {
-		dwarf64 = true
 		voff = 12
 	}
 
@@ -73,78 +119,58 @@ func ReadDwarfLengthVersion(data []byte) (length uint64, dwarf64 bool, version u
 
 	byteOrder = binary.LittleEndian
 	x, y := data[voff], data[voff+1]
-	switch {
-	default:
-		fallthrough
-	case x == 0 && y == 0:
-		version = 0
-		byteOrder = binary.LittleEndian
-	case x == 0:
-		version = y
+	if x == 0 && y != 0 {
 		byteOrder = binary.BigEndian
-	case y == 0:
-		version = x
-		byteOrder = binary.LittleEndian
-	}
-
-	if dwarf64 {
-		length = byteOrder.Uint64(data[4:])
-	} else {
-		length = uint64(byteOrder.Uint32(data))
 	}
 
+	length, dwarf64, version = ReadDwarfLengthVersionOrder(data, byteOrder)
 	return length, dwarf64, version, byteOrder
 }
 
-const (
-	_DW_UT_compile = 0x1 + iota
-	_DW_UT_type
-	_DW_UT_partial
-	_DW_UT_skeleton
-	_DW_UT_split_compile
-	_DW_UT_split_type
-)
-
-// ReadUnitVersions reads the DWARF version of each unit in a debug_info section and returns them as a map.
-func ReadUnitVersions(data []byte) map[dwarf.Offset]uint8 {
-	r := make(map[dwarf.Offset]uint8)
-	off := dwarf.Offset(0)
-	for len(data) > 0 {
-		length, dwarf64, version, _ := ReadDwarfLengthVersion(data)
-
-		data = data[4:]
-		off += 4
-		secoffsz := 4
-		if dwarf64 {
-			off += 8
-			secoffsz = 8
-			data = data[8:]
-		}
+// ReadDwarfLengthVersionOrder reads a DWARF length field followed by a
+// version field. order must be the byte order the section was encoded
+// with, as determined from the ELF/Mach-O/PE header delve has already
+// parsed; it is not something that can be reliably guessed from the unit
+// header itself (see ReadDwarfLengthVersion for the old, unreliable,
+// guessing approach kept around for compatibility).
+func ReadDwarfLengthVersionOrder(data []byte, order binary.ByteOrder) (length uint64, dwarf64 bool, version uint8) {
+	if len(data) < 4 {
+		return 0, false, 0
+	}
 
-		var headerSize int
+	lengthfield := order.Uint32(data)
+	voff := 4
+	if lengthfield == ^uint32(0) {
+		dwarf64 = true
+		voff = 12
+	}
 
-		switch version {
-		case 2, 3, 4:
-			headerSize = 3 + secoffsz
-		default: // 5 and later?
-			unitType := data[2]
+	if voff+1 >= len(data) {
+		return 0, false, 0
+	}
 
-			switch unitType {
-			case _DW_UT_compile, _DW_UT_partial:
-				headerSize = 4 + secoffsz
+	version = uint8(order.Uint16(data[voff:]))
 
-			case _DW_UT_skeleton, _DW_UT_split_compile:
-				headerSize = 4 + secoffsz + 8
+	if dwarf64 {
+		length = order.Uint64(data[4:])
+	} else {
+		length = uint64(lengthfield)
+	}
 
-			case _DW_UT_type, _DW_UT_split_type:
-				headerSize = 4 + secoffsz + 8 + secoffsz
-			}
-		}
+	return length, dwarf64, version
+}
 
-		r[off+dwarf.Offset(headerSize)] = version
+// ReadUnitVersions reads the DWARF version of each unit in a debug_info
+// section and returns them as a map, keyed by the offset of the unit's
+// first DIE. It is a thin wrapper around ReadUnitHeaders, for callers that
+// only care about the version and don't already know the byte order of
+// the section; see ReadUnitHeaders for the general case.
+func ReadUnitVersions(data []byte) map[dwarf.Offset]uint8 {
+	_, _, _, byteOrder := ReadDwarfLengthVersion(data)
 
-		data = data[length:] // skip contents
-		off += dwarf.Offset(length)
+	r := make(map[dwarf.Offset]uint8)
+	for _, hdr := range ReadUnitHeaders(data, byteOrder) {
+		r[hdr.Offset+dwarf.Offset(hdr.HeaderSize)] = hdr.Version
 	}
 	return r
 }
@@ -0,0 +1,114 @@
+package dwarf
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadUnitVersionsOffsets(t *testing.T) {
+	var data []byte
+	data = append(data, buildV4Unit(binary.LittleEndian, false, 4, 0x10, 8, 0x01)...)
+	v5off := len(data)
+	data = append(data, buildV5Unit(binary.LittleEndian, false, DW_UT_compile, 0x20, 8, nil, 0x02)...)
+	v3off := len(data)
+	data = append(data, buildV4Unit(binary.LittleEndian, true, 3, 0x30, 8, 0x03)...)
+
+	versions := ReadUnitVersions(data)
+
+	checks := []struct {
+		firstDIEOff int
+		wantVersion uint8
+	}{
+		{0 + 11, 4},     // dwarf32 v4: 4(len)+2(ver)+4(abbrev)+1(addrsize) = 11
+		{v5off + 12, 5}, // dwarf32 v5 compile: 4(len)+2(ver)+1(unittype)+1(addrsize)+4(abbrev) = 12
+		{v3off + 23, 3}, // dwarf64 v3: 12(len)+2(ver)+8(abbrev)+1(addrsize) = 23
+	}
+
+	for _, c := range checks {
+		got, ok := versions[dwarf.Offset(c.firstDIEOff)]
+		if !ok {
+			t.Errorf("no entry for offset %d (have %v)", c.firstDIEOff, versions)
+			continue
+		}
+		if got != c.wantVersion {
+			t.Errorf("offset %d: version = %d, want %d", c.firstDIEOff, got, c.wantVersion)
+		}
+	}
+}
+
+func TestReadDwarfLengthVersionGuessLittleEndian(t *testing.T) {
+	data := buildV4Unit(binary.LittleEndian, false, 4, 0x10, 8, 0x01)
+
+	length, dwarf64, version, order := ReadDwarfLengthVersion(data)
+	if dwarf64 {
+		t.Errorf("dwarf64 = true, want false")
+	}
+	if version != 4 {
+		t.Errorf("version = %d, want 4", version)
+	}
+	if order != binary.LittleEndian {
+		t.Errorf("order = %v, want LittleEndian", order)
+	}
+	wantLength, _, _ := ReadDwarfLengthVersionOrder(data, binary.LittleEndian)
+	if length != wantLength {
+		t.Errorf("length = %d, want %d", length, wantLength)
+	}
+}
+
+func TestReadDwarfLengthVersionOrderBigEndian(t *testing.T) {
+	data := buildV4Unit(binary.BigEndian, false, 4, 0x10, 8, 0x01)
+
+	length, dwarf64, version := ReadDwarfLengthVersionOrder(data, binary.BigEndian)
+	if dwarf64 {
+		t.Errorf("dwarf64 = true, want false")
+	}
+	if version != 4 {
+		t.Errorf("version = %d, want 4", version)
+	}
+	if length == 0 {
+		t.Errorf("length = 0, want nonzero")
+	}
+}
+
+func TestAppendUintReadUintRawAt(t *testing.T) {
+	for _, ptrSize := range []int{2, 4, 8} {
+		var buf []byte
+		buf = AppendUint(buf, binary.LittleEndian, ptrSize, 0x0102030405060708)
+
+		var want bytes.Buffer
+		if err := WriteUint(&want, binary.LittleEndian, ptrSize, 0x0102030405060708); err != nil {
+			t.Fatalf("ptrSize %d: WriteUint: %v", ptrSize, err)
+		}
+		if !bytes.Equal(buf, want.Bytes()) {
+			t.Errorf("ptrSize %d: AppendUint = %x, want %x", ptrSize, buf, want.Bytes())
+		}
+
+		v, off, err := ReadUintRawAt(buf, 0, binary.LittleEndian, ptrSize)
+		if err != nil {
+			t.Fatalf("ptrSize %d: ReadUintRawAt: %v", ptrSize, err)
+		}
+		if off != ptrSize {
+			t.Errorf("ptrSize %d: off = %d, want %d", ptrSize, off, ptrSize)
+		}
+
+		raw, err := ReadUintRaw(bytes.NewReader(buf), binary.LittleEndian, ptrSize)
+		if err != nil {
+			t.Fatalf("ptrSize %d: ReadUintRaw: %v", ptrSize, err)
+		}
+		if v != raw {
+			t.Errorf("ptrSize %d: ReadUintRawAt = %#x, want %#x", ptrSize, v, raw)
+		}
+	}
+}
+
+func TestReadUintRawAtTruncated(t *testing.T) {
+	buf := []byte{1, 2, 3}
+	if _, _, err := ReadUintRawAt(buf, 0, binary.LittleEndian, 8); err == nil {
+		t.Errorf("expected an error reading past the end of buf, got nil")
+	}
+	if _, _, err := ReadUintRawAt(buf, -1, binary.LittleEndian, 2); err == nil {
+		t.Errorf("expected an error for a negative offset, got nil")
+	}
+}
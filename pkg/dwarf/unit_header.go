@@ -0,0 +1,144 @@
+package dwarf
+
+import (
+	"debug/dwarf"
+	"encoding/binary"
+)
+
+// Unit types, see DWARF5 section 7.5.1, table 7.3.
+const (
+	DW_UT_compile = 0x1 + iota
+	DW_UT_type
+	DW_UT_partial
+	DW_UT_skeleton
+	DW_UT_split_compile
+	DW_UT_split_type
+)
+
+// UnitHeader describes the header of a single unit in a debug_info
+// section, as defined by DWARF5 section 7.5.1.1 through 7.5.1.3.
+type UnitHeader struct {
+	Offset       dwarf.Offset // offset of the unit, from the start of the section
+	HeaderSize   int          // size of the header, i.e. offset of the first DIE relative to Offset
+	Length       uint64       // length of the unit, not including the initial length field
+	Dwarf64      bool
+	Version      uint8
+	UnitType     uint8
+	AbbrevOffset uint64 // offset into debug_abbrev of this unit's abbreviation table
+	AddrSize     uint8
+
+	// DwoID is only valid for DW_UT_skeleton and DW_UT_split_compile units.
+	DwoID uint64
+
+	// TypeSignature and TypeOffset are only valid for DW_UT_type and
+	// DW_UT_split_type units.
+	TypeSignature uint64
+	TypeOffset    uint64
+}
+
+// ReadUnitHeaders parses the header of every unit contained in data, which
+// must be the raw contents of a debug_info section, and returns them in
+// section order. order must be the byte order the section was encoded
+// with. DWARF 2-4 headers are always read in their debug_info form: the
+// type_signature and type_offset fields of a DWARF 4 debug_types unit
+// header are not read, so HeaderSize is too small for such units.
+func ReadUnitHeaders(data []byte, order binary.ByteOrder) []UnitHeader {
+	var r []UnitHeader
+
+	off := dwarf.Offset(0)
+	for len(data) > 0 {
+		length, dwarf64, version := ReadDwarfLengthVersionOrder(data, order)
+		if version == 0 {
+			break
+		}
+
+		hdr := UnitHeader{Offset: off, Length: length, Dwarf64: dwarf64, Version: version}
+
+		lenfieldsz := 4
+		if dwarf64 {
+			lenfieldsz = 12
+		}
+		if lenfieldsz+2 > len(data) {
+			break
+		}
+		body := data[lenfieldsz+2:]
+
+		secoffsz := 4
+		if dwarf64 {
+			secoffsz = 8
+		}
+
+		// headerSize is relative to body, i.e. it doesn't yet include the
+		// initial length field and version field (lenfieldsz+2 bytes); that
+		// gets added in below, since hdr.HeaderSize must be relative to
+		// hdr.Offset, which points at the start of the initial length field.
+		ok := false
+		switch {
+		case version >= 2 && version <= 4:
+			if len(body) >= secoffsz+1 {
+				hdr.AbbrevOffset = readSecOffset(body, order, secoffsz)
+				hdr.AddrSize = body[secoffsz]
+				hdr.HeaderSize = secoffsz + 1
+				ok = true
+			}
+
+		case version >= 5:
+			if len(body) >= 2+secoffsz {
+				hdr.UnitType = body[0]
+				hdr.AddrSize = body[1]
+				hdr.AbbrevOffset = readSecOffset(body[2:], order, secoffsz)
+				rest := body[2+secoffsz:]
+
+				switch hdr.UnitType {
+				case DW_UT_compile, DW_UT_partial:
+					hdr.HeaderSize = 2 + secoffsz
+					ok = true
+
+				case DW_UT_skeleton, DW_UT_split_compile:
+					if len(rest) >= 8 {
+						hdr.DwoID = order.Uint64(rest)
+						hdr.HeaderSize = 2 + secoffsz + 8
+						ok = true
+					}
+
+				case DW_UT_type, DW_UT_split_type:
+					if len(rest) >= 8+secoffsz {
+						hdr.TypeSignature = order.Uint64(rest)
+						hdr.TypeOffset = readSecOffset(rest[8:], order, secoffsz)
+						hdr.HeaderSize = 2 + secoffsz + 8 + secoffsz
+						ok = true
+					}
+				}
+			}
+		}
+
+		if !ok {
+			break
+		}
+		hdr.HeaderSize += lenfieldsz + 2
+
+		r = append(r, hdr)
+
+		initialLengthSize := dwarf.Offset(4)
+		if dwarf64 {
+			initialLengthSize = 12
+		}
+		unitSize := initialLengthSize + dwarf.Offset(length)
+		if dwarf.Offset(len(data)) < unitSize {
+			break
+		}
+		data = data[unitSize:]
+		off += unitSize
+	}
+
+	return r
+}
+
+// readSecOffset reads a section-offset-sized (4 bytes for 32-bit DWARF, 8
+// bytes for 64-bit DWARF) value from the start of data.
+func readSecOffset(data []byte, order binary.ByteOrder, secoffsz int) uint64 {
+	if secoffsz == 8 {
+		return order.Uint64(data)
+	}
+	return uint64(order.Uint32(data))
+}
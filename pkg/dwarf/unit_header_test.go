@@ -0,0 +1,133 @@
+package dwarf
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// buildV4Unit builds a minimal DWARF 2-4 style unit: unit_length, version,
+// debug_abbrev_offset, address_size, followed by a single marker byte
+// standing in for the first DIE.
+func buildV4Unit(order binary.ByteOrder, dwarf64 bool, version uint16, abbrevOff uint64, addrSize uint8, marker byte) []byte {
+	secoffsz := 4
+	if dwarf64 {
+		secoffsz = 8
+	}
+
+	body := make([]byte, 0, 2+secoffsz+1+1)
+	body = AppendUint(body, order, 2, uint64(version))
+	body = AppendUint(body, order, secoffsz, abbrevOff)
+	body = append(body, addrSize, marker)
+
+	return prependLength(order, dwarf64, body)
+}
+
+// buildV5Unit builds a minimal DWARF5 unit of the given unit_type: version,
+// unit_type, address_size, debug_abbrev_offset, then unit-type-specific
+// trailing fields, then a single marker byte standing in for the first DIE.
+func buildV5Unit(order binary.ByteOrder, dwarf64 bool, unitType uint8, abbrevOff uint64, addrSize uint8, trailing []byte, marker byte) []byte {
+	secoffsz := 4
+	if dwarf64 {
+		secoffsz = 8
+	}
+
+	body := make([]byte, 0, 2+2+secoffsz+len(trailing)+1)
+	body = AppendUint(body, order, 2, 5)
+	body = append(body, unitType, addrSize)
+	body = AppendUint(body, order, secoffsz, abbrevOff)
+	body = append(body, trailing...)
+	body = append(body, marker)
+
+	return prependLength(order, dwarf64, body)
+}
+
+func prependLength(order binary.ByteOrder, dwarf64 bool, body []byte) []byte {
+	var buf []byte
+	if dwarf64 {
+		buf = AppendUint(buf, order, 4, ^uint64(0)>>32) // 0xffffffff
+		buf = AppendUint(buf, order, 8, uint64(len(body)))
+	} else {
+		buf = AppendUint(buf, order, 4, uint64(len(body)))
+	}
+	return append(buf, body...)
+}
+
+func TestReadUnitHeadersFirstDIEOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		marker byte
+	}{
+		{"v4 dwarf32", buildV4Unit(binary.LittleEndian, false, 4, 0x11223344, 8, 0xab), 0xab},
+		{"v4 dwarf64", buildV4Unit(binary.LittleEndian, true, 4, 0x1122334455667788, 8, 0xcd), 0xcd},
+		{"v5 compile dwarf32", buildV5Unit(binary.LittleEndian, false, DW_UT_compile, 0x44332211, 8, nil, 0xef), 0xef},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hdrs := ReadUnitHeaders(test.data, binary.LittleEndian)
+			if len(hdrs) != 1 {
+				t.Fatalf("expected 1 unit header, got %d", len(hdrs))
+			}
+			hdr := hdrs[0]
+
+			firstDIE := int(hdr.Offset) + hdr.HeaderSize
+			if firstDIE >= len(test.data) {
+				t.Fatalf("computed first DIE offset %d is out of bounds (len %d)", firstDIE, len(test.data))
+			}
+			if test.data[firstDIE] != test.marker {
+				t.Errorf("Offset+HeaderSize = %d, expected marker byte %#x there, got %#x", firstDIE, test.marker, test.data[firstDIE])
+			}
+		})
+	}
+}
+
+func TestReadUnitHeadersSkeleton(t *testing.T) {
+	dwoID := uint64(0xdeadbeefcafebabe)
+	trailing := AppendUint(nil, binary.LittleEndian, 8, dwoID)
+	data := buildV5Unit(binary.LittleEndian, false, DW_UT_skeleton, 0x100, 8, trailing, 0x5a)
+
+	hdrs := ReadUnitHeaders(data, binary.LittleEndian)
+	if len(hdrs) != 1 {
+		t.Fatalf("expected 1 unit header, got %d", len(hdrs))
+	}
+	hdr := hdrs[0]
+
+	if hdr.DwoID != dwoID {
+		t.Errorf("DwoID = %#x, want %#x", hdr.DwoID, dwoID)
+	}
+
+	firstDIE := int(hdr.Offset) + hdr.HeaderSize
+	if data[firstDIE] != 0x5a {
+		t.Errorf("Offset+HeaderSize = %d, expected marker byte there, got %#x", firstDIE, data[firstDIE])
+	}
+}
+
+func TestReadUnitHeadersUnrecognizedUnitType(t *testing.T) {
+	data := buildV5Unit(binary.LittleEndian, false, 0x99, 0x10, 8, nil, 0xff)
+
+	hdrs := ReadUnitHeaders(data, binary.LittleEndian)
+	if len(hdrs) != 0 {
+		t.Fatalf("expected parsing to stop on unrecognized unit_type, got %d headers", len(hdrs))
+	}
+}
+
+func TestReadUnitHeadersMultipleUnits(t *testing.T) {
+	var data []byte
+	data = append(data, buildV4Unit(binary.LittleEndian, false, 4, 0x10, 8, 0x01)...)
+	data = append(data, buildV5Unit(binary.LittleEndian, false, DW_UT_compile, 0x20, 8, nil, 0x02)...)
+	data = append(data, buildV4Unit(binary.LittleEndian, true, 3, 0x30, 8, 0x03)...)
+
+	hdrs := ReadUnitHeaders(data, binary.LittleEndian)
+	if len(hdrs) != 3 {
+		t.Fatalf("expected 3 unit headers, got %d", len(hdrs))
+	}
+
+	wantMarkers := []byte{0x01, 0x02, 0x03}
+	for i, hdr := range hdrs {
+		firstDIE := int(hdr.Offset) + hdr.HeaderSize
+		if data[firstDIE] != wantMarkers[i] {
+			t.Errorf("unit %d: Offset+HeaderSize = %d, expected marker %#x there, got %#x", i, firstDIE, wantMarkers[i], data[firstDIE])
+		}
+	}
+}